@@ -0,0 +1,76 @@
+package discovery
+
+import "sync"
+
+// baseInstancer implements the channel bookkeeping shared by every
+// Instancer: tracking registered channels and broadcasting the latest Event
+// to each of them. Concrete Instancers (consulInstancer, etcdInstancer)
+// embed it and call broadcast whenever their watch observes a change.
+//
+// Register and broadcast never send on a channel while holding mu: ch is an
+// unbuffered chan<- Event whose reader (typically Endpointer.receive) may not
+// be running yet, or may be slow. Sending under the lock would block every
+// other Register/Deregister/broadcast call - and, through that, any caller
+// holding a lock of its own while it registers - on that one reader.
+//
+// That leaves a second problem: a caller of Deregister (typically
+// Endpointer.Close, right before it closes ch) needs to know no Register or
+// broadcast call still holds a reference to ch and might send on it after
+// it's closed. sendMu is the fence for that: every send happens while
+// holding it, and Deregister acquires-then-releases it after removing ch
+// from the map, so by the time Deregister returns, no send that raced with
+// it - whether it captured ch before or the map already reflects the
+// removal - can still be in flight.
+type baseInstancer struct {
+	mu       sync.Mutex
+	channels map[chan<- Event]struct{}
+	cache    Event
+
+	sendMu sync.Mutex
+}
+
+func newBaseInstancer() *baseInstancer {
+	return &baseInstancer{
+		channels: map[chan<- Event]struct{}{},
+	}
+}
+
+func (b *baseInstancer) Register(ch chan<- Event) {
+	b.mu.Lock()
+	b.channels[ch] = struct{}{}
+	initial := b.cache
+	b.mu.Unlock()
+
+	b.sendMu.Lock()
+	defer b.sendMu.Unlock()
+	ch <- initial
+}
+
+// Deregister removes ch from the notified set and blocks until any Register
+// or broadcast call already holding ch (because it read the channel set
+// before this call's delete took effect) has finished sending to it. Once
+// Deregister returns, the caller can safely close ch.
+func (b *baseInstancer) Deregister(ch chan<- Event) {
+	b.mu.Lock()
+	delete(b.channels, ch)
+	b.mu.Unlock()
+
+	b.sendMu.Lock()
+	b.sendMu.Unlock()
+}
+
+func (b *baseInstancer) broadcast(e Event) {
+	b.mu.Lock()
+	b.cache = e
+	chans := make([]chan<- Event, 0, len(b.channels))
+	for ch := range b.channels {
+		chans = append(chans, ch)
+	}
+	b.mu.Unlock()
+
+	b.sendMu.Lock()
+	defer b.sendMu.Unlock()
+	for _, ch := range chans {
+		ch <- e
+	}
+}
@@ -0,0 +1,79 @@
+package discovery
+
+import (
+	"time"
+
+	consul "github.com/hashicorp/consul/api"
+	"go.uber.org/zap"
+)
+
+// consulInstancer watches a single Consul service name via blocking queries
+// against the health API, reporting only passing instances.
+type consulInstancer struct {
+	*baseInstancer
+
+	client  *consul.Health
+	service string
+	tag     string
+	log     *zap.Logger
+
+	stopCh chan struct{}
+}
+
+// NewConsulInstancer returns an Instancer that watches service (optionally
+// filtered by tag) using client's health endpoint.
+func NewConsulInstancer(client *consul.Client, service, tag string, log *zap.Logger) Instancer {
+	i := &consulInstancer{
+		baseInstancer: newBaseInstancer(),
+		client:        client.Health(),
+		service:       service,
+		tag:           tag,
+		log:           log,
+		stopCh:        make(chan struct{}),
+	}
+	go i.watch()
+	return i
+}
+
+func (i *consulInstancer) watch() {
+	var lastIndex uint64
+
+	for {
+		select {
+		case <-i.stopCh:
+			return
+		default:
+		}
+
+		entries, meta, err := i.client.Service(i.service, i.tag, true, &consul.QueryOptions{
+			WaitIndex: lastIndex,
+			WaitTime:  10 * time.Second,
+		})
+		if err != nil {
+			i.log.Error("Consul health query failed", zap.String("service", i.service), zap.Error(err))
+			i.broadcast(Event{Err: err})
+			time.Sleep(time.Second)
+			continue
+		}
+
+		if meta.LastIndex == lastIndex {
+			continue
+		}
+		lastIndex = meta.LastIndex
+
+		instances := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			addr := entry.Service.Address
+			if addr == "" {
+				addr = entry.Node.Address
+			}
+			instances = append(instances, addr)
+		}
+
+		i.broadcast(Event{Instances: instances})
+	}
+}
+
+func (i *consulInstancer) Stop() {
+	close(i.stopCh)
+}
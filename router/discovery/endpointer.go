@@ -0,0 +1,131 @@
+package discovery
+
+import (
+	"io"
+	"sort"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/serverless/gateway/functions"
+)
+
+// Factory turns a single instance (e.g. "10.0.1.4:8080") into an invocable
+// functions.Function, along with an io.Closer that releases whatever
+// connection or resource the Function holds once the instance disappears.
+type Factory func(instance string) (functions.Function, io.Closer, error)
+
+// Endpointer maintains the set of functions.Function invocation targets for
+// a single FunctionID by converting each instance an Instancer reports into
+// a target via Factory, caching successful conversions and evicting them
+// once their backing instance disappears.
+type Endpointer struct {
+	instancer Instancer
+	factory   Factory
+	log       *zap.Logger
+
+	ch chan Event
+
+	mu        sync.RWMutex
+	endpoints map[string]endpointCacheEntry
+}
+
+type endpointCacheEntry struct {
+	fn     functions.Function
+	closer io.Closer
+}
+
+// NewEndpointer constructs an Endpointer and starts it watching instancer.
+func NewEndpointer(instancer Instancer, factory Factory, log *zap.Logger) *Endpointer {
+	e := &Endpointer{
+		instancer: instancer,
+		factory:   factory,
+		log:       log,
+		ch:        make(chan Event),
+		endpoints: map[string]endpointCacheEntry{},
+	}
+
+	// receive must be running before Register, since Register's initial
+	// delivery is a synchronous send on e.ch: registering first would
+	// deadlock waiting for a reader that doesn't exist yet.
+	go e.receive()
+	instancer.Register(e.ch)
+
+	return e
+}
+
+func (e *Endpointer) receive() {
+	for event := range e.ch {
+		e.updateCache(event)
+	}
+}
+
+func (e *Endpointer) updateCache(event Event) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if event.Err != nil {
+		e.log.Error("Instancer reported an error, keeping last known-good endpoints", zap.Error(event.Err))
+		return
+	}
+
+	keep := map[string]struct{}{}
+	for _, instance := range event.Instances {
+		keep[instance] = struct{}{}
+
+		if _, cached := e.endpoints[instance]; cached {
+			continue
+		}
+
+		fn, closer, err := e.factory(instance)
+		if err != nil {
+			e.log.Error("Could not build a Function for discovered instance", zap.String("instance", instance), zap.Error(err))
+			continue
+		}
+		e.endpoints[instance] = endpointCacheEntry{fn: fn, closer: closer}
+	}
+
+	for instance, entry := range e.endpoints {
+		if _, ok := keep[instance]; ok {
+			continue
+		}
+		if entry.closer != nil {
+			entry.closer.Close()
+		}
+		delete(e.endpoints, instance)
+	}
+}
+
+// Endpoints returns the current set of live invocation targets, ordered by
+// instance so that successive calls see the same order.
+func (e *Endpointer) Endpoints() []functions.Function {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	instances := make([]string, 0, len(e.endpoints))
+	for instance := range e.endpoints {
+		instances = append(instances, instance)
+	}
+	sort.Strings(instances)
+
+	fns := make([]functions.Function, 0, len(instances))
+	for _, instance := range instances {
+		fns = append(fns, e.endpoints[instance].fn)
+	}
+	return fns
+}
+
+// Close stops watching the Instancer and closes every cached endpoint.
+func (e *Endpointer) Close() {
+	e.instancer.Deregister(e.ch)
+	close(e.ch)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for instance, entry := range e.endpoints {
+		if entry.closer != nil {
+			entry.closer.Close()
+		}
+		delete(e.endpoints, instance)
+	}
+}
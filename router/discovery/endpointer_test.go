@@ -0,0 +1,171 @@
+package discovery
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/serverless/gateway/functions"
+)
+
+// fakeInstancer stands in for a real service-registry watcher (Consul, etcd)
+// in tests: it embeds baseInstancer for the Register/Deregister/broadcast
+// plumbing consulInstancer also embeds, and lets the test push Events
+// directly instead of running blocking queries against a live Consul.
+type fakeInstancer struct {
+	*baseInstancer
+}
+
+func newFakeInstancer() *fakeInstancer {
+	return &fakeInstancer{baseInstancer: newBaseInstancer()}
+}
+
+func (f *fakeInstancer) push(e Event) { f.broadcast(e) }
+
+func (f *fakeInstancer) Stop() {}
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
+
+// TestNewEndpointerRegistersWithoutDeadlocking is a regression test:
+// NewEndpointer used to call instancer.Register before starting its receive
+// goroutine, and Register's initial delivery is a synchronous channel send,
+// so the very first registration against any Instancer would hang forever
+// with no reader. If this test times out, that regression is back.
+func TestNewEndpointerRegistersWithoutDeadlocking(t *testing.T) {
+	instancer := newFakeInstancer()
+
+	done := make(chan *Endpointer, 1)
+	go func() {
+		done <- NewEndpointer(instancer, func(instance string) (functions.Function, io.Closer, error) {
+			return functions.Function{ID: functions.FunctionID(instance)}, nil, nil
+		}, zap.NewNop())
+	}()
+
+	select {
+	case e := <-done:
+		e.Close()
+	case <-time.After(time.Second):
+		t.Fatal("NewEndpointer did not return - Register likely deadlocked")
+	}
+}
+
+func TestEndpointerTracksInstancerEvents(t *testing.T) {
+	instancer := newFakeInstancer()
+	closed := map[string]bool{}
+
+	factory := func(instance string) (functions.Function, io.Closer, error) {
+		return functions.Function{ID: functions.FunctionID(instance)}, closerFunc(func() error {
+			closed[instance] = true
+			return nil
+		}), nil
+	}
+
+	e := NewEndpointer(instancer, factory, zap.NewNop())
+	defer e.Close()
+
+	instancer.push(Event{Instances: []string{"10.0.0.1", "10.0.0.2"}})
+	waitForEndpointCount(t, e, 2)
+
+	balancer := NewRoundRobin(e)
+	seen := map[functions.FunctionID]struct{}{}
+	for i := 0; i < 4; i++ {
+		fn, err := balancer.Choose()
+		if err != nil {
+			t.Fatalf("Choose: %v", err)
+		}
+		seen[fn.ID] = struct{}{}
+	}
+	if len(seen) != 2 {
+		t.Fatalf("round robin saw %d distinct instances, want 2", len(seen))
+	}
+
+	instancer.push(Event{Instances: []string{"10.0.0.2"}})
+	waitForEndpointCount(t, e, 1)
+
+	if !closed["10.0.0.1"] {
+		t.Fatal("evicted instance's Closer was never called")
+	}
+}
+
+func TestEndpointerKeepsLastKnownGoodOnInstancerError(t *testing.T) {
+	instancer := newFakeInstancer()
+	factory := func(instance string) (functions.Function, io.Closer, error) {
+		return functions.Function{ID: functions.FunctionID(instance)}, nil, nil
+	}
+
+	e := NewEndpointer(instancer, factory, zap.NewNop())
+	defer e.Close()
+
+	instancer.push(Event{Instances: []string{"10.0.0.1"}})
+	waitForEndpointCount(t, e, 1)
+
+	instancer.push(Event{Err: errors.New("watch failed")})
+	// An error from the Instancer must not clear out endpoints already known
+	// to be good.
+	time.Sleep(10 * time.Millisecond)
+	waitForEndpointCount(t, e, 1)
+}
+
+func TestRoundRobinReturnsErrNoEndpointsWhenEmpty(t *testing.T) {
+	instancer := newFakeInstancer()
+	e := NewEndpointer(instancer, func(instance string) (functions.Function, io.Closer, error) {
+		return functions.Function{}, nil, nil
+	}, zap.NewNop())
+	defer e.Close()
+
+	if _, err := NewRoundRobin(e).Choose(); err != ErrNoEndpoints {
+		t.Fatalf("Choose() = %v, want ErrNoEndpoints", err)
+	}
+}
+
+// TestEndpointerCloseRacesWithBroadcast is a regression test for a panic
+// where Close could close e.ch while a concurrent broadcast - having already
+// snapshotted e.ch before Deregister removed it - was still sending on it.
+// Run with -race to catch the "send on closed channel" / chansend-vs-
+// closechan race directly; without -race this at least exercises the
+// interleaving repeatedly without panicking.
+func TestEndpointerCloseRacesWithBroadcast(t *testing.T) {
+	instancer := newFakeInstancer()
+	factory := func(instance string) (functions.Function, io.Closer, error) {
+		return functions.Function{ID: functions.FunctionID(instance)}, nil, nil
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			instancer.push(Event{Instances: []string{"10.0.0.1"}})
+		}
+	}()
+
+	for i := 0; i < 50; i++ {
+		e := NewEndpointer(instancer, factory, zap.NewNop())
+		e.Close()
+	}
+
+	close(stop)
+	<-done
+}
+
+func waitForEndpointCount(t *testing.T, e *Endpointer, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if len(e.Endpoints()) == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("endpoints never reached count %d (have %d)", n, len(e.Endpoints()))
+}
@@ -0,0 +1,75 @@
+package discovery
+
+import (
+	"context"
+
+	etcd "github.com/coreos/etcd/clientv3"
+	"go.uber.org/zap"
+)
+
+// etcdInstancer watches a key prefix in etcd, treating the value of every
+// key under the prefix as one instance address.
+type etcdInstancer struct {
+	*baseInstancer
+
+	client *etcd.Client
+	prefix string
+	log    *zap.Logger
+
+	cancel context.CancelFunc
+}
+
+// NewEtcdInstancer returns an Instancer that watches every key under prefix,
+// treating each key's value as an instance address.
+func NewEtcdInstancer(client *etcd.Client, prefix string, log *zap.Logger) Instancer {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	i := &etcdInstancer{
+		baseInstancer: newBaseInstancer(),
+		client:        client,
+		prefix:        prefix,
+		log:           log,
+		cancel:        cancel,
+	}
+
+	go i.watch(ctx)
+	return i
+}
+
+func (i *etcdInstancer) watch(ctx context.Context) {
+	if err := i.refresh(ctx); err != nil {
+		i.broadcast(Event{Err: err})
+	}
+
+	watchCh := i.client.Watch(ctx, i.prefix, etcd.WithPrefix())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-watchCh:
+			if err := i.refresh(ctx); err != nil {
+				i.log.Error("Could not refresh etcd instance list", zap.String("prefix", i.prefix), zap.Error(err))
+				i.broadcast(Event{Err: err})
+			}
+		}
+	}
+}
+
+func (i *etcdInstancer) refresh(ctx context.Context) error {
+	resp, err := i.client.Get(ctx, i.prefix, etcd.WithPrefix())
+	if err != nil {
+		return err
+	}
+
+	instances := make([]string, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		instances = append(instances, string(kv.Value))
+	}
+
+	i.broadcast(Event{Instances: instances})
+	return nil
+}
+
+func (i *etcdInstancer) Stop() {
+	i.cancel()
+}
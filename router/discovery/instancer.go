@@ -0,0 +1,29 @@
+// Package discovery resolves a functions.FunctionID to a live set of backend
+// instances discovered from a service registry (Consul, etcd, ...), modeled
+// on go-kit's sd.Instancer / sd.Endpointer split: an Instancer watches a
+// service name and pushes Events; an Endpointer turns those into typed
+// invocation targets.
+package discovery
+
+// Event is pushed by an Instancer whenever the set of instances backing a
+// watched service changes, or whenever the watch itself fails.
+type Event struct {
+	Instances []string
+	Err       error
+}
+
+// Instancer watches a single service name and notifies registered channels
+// of Events as the instance set changes. Implementations must be safe for
+// concurrent use.
+type Instancer interface {
+	// Register adds ch to the set of channels notified of Events. The
+	// current instance set (or the last error) is sent immediately so
+	// callers don't have to wait for the next change to get a picture of
+	// the world.
+	Register(ch chan<- Event)
+	// Deregister removes ch from the notified set.
+	Deregister(ch chan<- Event)
+	// Stop releases any resources held by the watch (connections,
+	// goroutines). The Instancer must not be used afterwards.
+	Stop()
+}
@@ -0,0 +1,119 @@
+package discovery
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/serverless/gateway/functions"
+)
+
+// ErrNoEndpoints is returned by a Balancer when its Endpointer currently has
+// no live instances to choose from.
+var ErrNoEndpoints = errors.New("discovery: no endpoints available")
+
+// Balancer picks one functions.Function out of an Endpointer's current
+// instance set.
+type Balancer interface {
+	Choose() (functions.Function, error)
+}
+
+// roundRobin cycles through the Endpointer's instances in the order
+// Endpoints() returns them.
+type roundRobin struct {
+	endpointer *Endpointer
+
+	mu   sync.Mutex
+	next int
+}
+
+// NewRoundRobin returns a Balancer that cycles through endpointer's
+// instances in order.
+func NewRoundRobin(endpointer *Endpointer) Balancer {
+	return &roundRobin{endpointer: endpointer}
+}
+
+func (r *roundRobin) Choose() (functions.Function, error) {
+	endpoints := r.endpointer.Endpoints()
+	if len(endpoints) == 0 {
+		return functions.Function{}, ErrNoEndpoints
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.next = (r.next + 1) % len(endpoints)
+	return endpoints[r.next], nil
+}
+
+// random picks a uniformly random instance out of the Endpointer's current
+// set on every call.
+type random struct {
+	endpointer *Endpointer
+	rand       *rand.Rand
+	mu         sync.Mutex
+}
+
+// NewRandom returns a Balancer that picks a uniformly random instance out of
+// endpointer's current set on every call.
+func NewRandom(endpointer *Endpointer, seed int64) Balancer {
+	return &random{endpointer: endpointer, rand: rand.New(rand.NewSource(seed))}
+}
+
+func (r *random) Choose() (functions.Function, error) {
+	endpoints := r.endpointer.Endpoints()
+	if len(endpoints) == 0 {
+		return functions.Function{}, ErrNoEndpoints
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return endpoints[r.rand.Intn(len(endpoints))], nil
+}
+
+// Retry wraps a Balancer so that Choose is retried against a freshly chosen
+// instance (useful when the chosen instance turns out to be unreachable) up
+// to max times or until timeout elapses, whichever comes first.
+type Retry struct {
+	balancer Balancer
+	max      int
+	timeout  time.Duration
+}
+
+// NewRetry wraps balancer, retrying a failed Choose up to max times within
+// timeout.
+func NewRetry(balancer Balancer, max int, timeout time.Duration) *Retry {
+	return &Retry{balancer: balancer, max: max, timeout: timeout}
+}
+
+// Choose returns the first Balancer.Choose call to succeed within max
+// attempts and timeout. should is invoked with each candidate; a nil error
+// accepts it, any other error triggers the next attempt.
+func (r *Retry) Choose(should func(functions.Function) error) (functions.Function, error) {
+	deadline := time.Now().Add(r.timeout)
+	var lastErr error
+
+	for attempt := 0; attempt < r.max; attempt++ {
+		if time.Now().After(deadline) {
+			break
+		}
+
+		fn, err := r.balancer.Choose()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		if err := should(fn); err != nil {
+			lastErr = err
+			continue
+		}
+
+		return fn, nil
+	}
+
+	if lastErr == nil {
+		lastErr = ErrNoEndpoints
+	}
+	return functions.Function{}, lastErr
+}
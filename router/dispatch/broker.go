@@ -0,0 +1,43 @@
+package dispatch
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/serverless/gateway/event"
+	"github.com/serverless/gateway/functions"
+)
+
+// Broker is the one operation a message-broker Transport needs: publish a
+// payload to a named topic. NATSBroker and KafkaBroker are thin adapters
+// over their respective client libraries.
+type Broker interface {
+	Publish(topic string, payload []byte) error
+}
+
+// BrokerTransport publishes an Event to a topic derived from the Function's
+// pubsub.TopicID instead of invoking it synchronously, so downstream
+// consumers can pull it asynchronously off the broker.
+type BrokerTransport struct {
+	Broker Broker
+	// TopicFor resolves the broker topic a Function's events should be
+	// published to.
+	TopicFor func(*functions.Function) string
+}
+
+// Send publishes evt and returns immediately; there is no synchronous
+// response from the eventual consumer, so Response only reports that the
+// publish succeeded.
+func (t *BrokerTransport) Send(ctx context.Context, fn *functions.Function, evt *event.Event) (*Response, error) {
+	payload, err := json.Marshal(evt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := t.Broker.Publish(t.TopicFor(fn), payload); err != nil {
+		return nil, err
+	}
+
+	return &Response{StatusCode: http.StatusAccepted}, nil
+}
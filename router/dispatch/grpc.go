@@ -0,0 +1,86 @@
+package dispatch
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/grpc"
+
+	"github.com/serverless/gateway/event"
+	"github.com/serverless/gateway/functions"
+)
+
+// GRPCTransport dispatches Events over gRPC. It keeps one persistent
+// *grpc.ClientConn per FunctionID so repeated invocations reuse a
+// connection instead of paying dial cost every time; the actual RPC is left
+// to Invoke, which wraps whatever generated client stub the gateway's gRPC
+// contract uses.
+type GRPCTransport struct {
+	// Dial opens a new connection to a Function's provider URL.
+	Dial func(target string) (*grpc.ClientConn, error)
+	// Invoke performs the RPC over an already-dialed connection.
+	Invoke func(ctx context.Context, conn *grpc.ClientConn, fn *functions.Function, evt *event.Event) (*Response, error)
+
+	mu      sync.Mutex
+	conns   map[functions.FunctionID]*grpc.ClientConn
+	targets map[functions.FunctionID]string
+}
+
+// NewGRPCTransport returns a GRPCTransport that dials with dial and performs
+// RPCs with invoke.
+func NewGRPCTransport(dial func(target string) (*grpc.ClientConn, error), invoke func(ctx context.Context, conn *grpc.ClientConn, fn *functions.Function, evt *event.Event) (*Response, error)) *GRPCTransport {
+	return &GRPCTransport{
+		Dial:    dial,
+		Invoke:  invoke,
+		conns:   map[functions.FunctionID]*grpc.ClientConn{},
+		targets: map[functions.FunctionID]string{},
+	}
+}
+
+// connFor returns the cached conn for fn.ID, redialing if fn.Provider.URL
+// has changed since it was cached (e.g. a redeploy moved the Function to a
+// new address) so Send never sticks with a stale target.
+func (t *GRPCTransport) connFor(fn *functions.Function) (*grpc.ClientConn, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if conn, ok := t.conns[fn.ID]; ok {
+		if t.targets[fn.ID] == fn.Provider.URL {
+			return conn, nil
+		}
+		conn.Close()
+		delete(t.conns, fn.ID)
+		delete(t.targets, fn.ID)
+	}
+
+	conn, err := t.Dial(fn.Provider.URL)
+	if err != nil {
+		return nil, err
+	}
+	t.conns[fn.ID] = conn
+	t.targets[fn.ID] = fn.Provider.URL
+	return conn, nil
+}
+
+// Evict closes and forgets the cached conn for id, if any. Callers that
+// delete or reconfigure a Function's Transport must call this, otherwise
+// the conn cached here outlives the Function and leaks.
+func (t *GRPCTransport) Evict(id functions.FunctionID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if conn, ok := t.conns[id]; ok {
+		conn.Close()
+		delete(t.conns, id)
+	}
+	delete(t.targets, id)
+}
+
+func (t *GRPCTransport) Send(ctx context.Context, fn *functions.Function, evt *event.Event) (*Response, error) {
+	conn, err := t.connFor(fn)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.Invoke(ctx, conn, fn, evt)
+}
@@ -0,0 +1,73 @@
+package dispatch
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+
+	"github.com/serverless/gateway/event"
+	"github.com/serverless/gateway/functions"
+)
+
+func TestGRPCTransportRedialsWhenProviderURLChanges(t *testing.T) {
+	var dialed []string
+	dial := func(target string) (*grpc.ClientConn, error) {
+		dialed = append(dialed, target)
+		return grpc.Dial(target, grpc.WithInsecure())
+	}
+	invoke := func(ctx context.Context, conn *grpc.ClientConn, fn *functions.Function, evt *event.Event) (*Response, error) {
+		return &Response{}, nil
+	}
+
+	transport := NewGRPCTransport(dial, invoke)
+	fn := &functions.Function{ID: "fn-a"}
+	fn.Provider.URL = "host-a:1"
+
+	if _, err := transport.Send(context.Background(), fn, &event.Event{}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if _, err := transport.Send(context.Background(), fn, &event.Event{}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if len(dialed) != 1 {
+		t.Fatalf("dialed %d times, want 1 (conn should be reused)", len(dialed))
+	}
+
+	fn.Provider.URL = "host-b:1"
+	if _, err := transport.Send(context.Background(), fn, &event.Event{}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if len(dialed) != 2 {
+		t.Fatalf("dialed %d times, want 2 after Provider.URL changed", len(dialed))
+	}
+}
+
+func TestGRPCTransportEvictClosesAndForgetsConn(t *testing.T) {
+	dial := func(target string) (*grpc.ClientConn, error) {
+		return grpc.Dial(target, grpc.WithInsecure())
+	}
+	invoke := func(ctx context.Context, conn *grpc.ClientConn, fn *functions.Function, evt *event.Event) (*Response, error) {
+		return &Response{}, nil
+	}
+
+	transport := NewGRPCTransport(dial, invoke)
+	fn := &functions.Function{ID: "fn-a"}
+	fn.Provider.URL = "host-a:1"
+
+	if _, err := transport.Send(context.Background(), fn, &event.Event{}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	transport.Evict(fn.ID)
+
+	if _, ok := transport.conns[fn.ID]; ok {
+		t.Fatal("Evict left the conn cached")
+	}
+	if _, ok := transport.targets[fn.ID]; ok {
+		t.Fatal("Evict left the target cached")
+	}
+
+	// Evict on an id that was never dialed must be a no-op, not a panic.
+	transport.Evict("never-dialed")
+}
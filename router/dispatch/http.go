@@ -0,0 +1,54 @@
+package dispatch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/serverless/gateway/event"
+	"github.com/serverless/gateway/functions"
+)
+
+// HTTPTransport is the dispatch behavior the router has always had: POST the
+// Event as JSON to the Function's provider URL.
+type HTTPTransport struct {
+	Client *http.Client
+}
+
+// NewHTTPTransport returns an HTTPTransport using client, or
+// http.DefaultClient if client is nil.
+func NewHTTPTransport(client *http.Client) *HTTPTransport {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &HTTPTransport{Client: client}
+}
+
+func (t *HTTPTransport) Send(ctx context.Context, fn *functions.Function, evt *event.Event) (*Response, error) {
+	body, err := json.Marshal(evt)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, fn.Provider.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Response{StatusCode: resp.StatusCode, Body: respBody}, nil
+}
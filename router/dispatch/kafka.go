@@ -0,0 +1,17 @@
+package dispatch
+
+import "github.com/Shopify/sarama"
+
+// KafkaBroker publishes to a Kafka topic via an already-configured
+// synchronous producer.
+type KafkaBroker struct {
+	Producer sarama.SyncProducer
+}
+
+func (b *KafkaBroker) Publish(topic string, payload []byte) error {
+	_, _, err := b.Producer.SendMessage(&sarama.ProducerMessage{
+		Topic: topic,
+		Value: sarama.ByteEncoder(payload),
+	})
+	return err
+}
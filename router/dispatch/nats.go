@@ -0,0 +1,12 @@
+package dispatch
+
+import nats "github.com/nats-io/go-nats"
+
+// NATSBroker publishes to a NATS subject via an already-connected client.
+type NATSBroker struct {
+	Conn *nats.Conn
+}
+
+func (b *NATSBroker) Publish(topic string, payload []byte) error {
+	return b.Conn.Publish(topic, payload)
+}
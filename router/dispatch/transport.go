@@ -0,0 +1,31 @@
+// Package dispatch decouples the router from how a matched Function is
+// actually invoked. Historically that was always an HTTP POST; Transport
+// lets it also be a gRPC call or an asynchronous publish to a message
+// broker, selected per Function.
+package dispatch
+
+import (
+	"context"
+
+	"github.com/serverless/gateway/event"
+	"github.com/serverless/gateway/functions"
+)
+
+// Response is what a Transport returns after delivering an Event.
+type Response struct {
+	StatusCode int
+	Body       []byte
+}
+
+// Transport delivers a single Event to a Function's backend.
+type Transport interface {
+	Send(ctx context.Context, fn *functions.Function, evt *event.Event) (*Response, error)
+}
+
+// Evictor is implemented by a Transport that caches per-Function state (e.g.
+// GRPCTransport's per-conn cache). Callers that delete a Function or
+// reconfigure its Transport must call Evict so that state doesn't outlive
+// the Function it was built for.
+type Evictor interface {
+	Evict(id functions.FunctionID)
+}
@@ -0,0 +1,76 @@
+package dispatch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/grpc"
+
+	"github.com/serverless/gateway/event"
+	"github.com/serverless/gateway/functions"
+)
+
+// fakeBroker stands in for a NATS/Kafka connection in the broker benchmark:
+// Publish is a no-op that does no real network I/O, so the benchmark measures BrokerTransport's own per-Send overhead
+// rather than a broker round trip neither client library can make here.
+type fakeBroker struct{}
+
+func (fakeBroker) Publish(topic string, payload []byte) error { return nil }
+
+// BenchmarkTransports compares the per-Send overhead of the three dispatch
+// Transports at the kind of fan-out rate SubscribersOfEvent needs to sustain
+// (on the order of 10k events/sec): HTTPTransport against a real local
+// httptest.Server, and GRPCTransport/BrokerTransport (standing in for the
+// NATS/Kafka brokers) against fakes that skip the actual dial/network hop,
+// since no gRPC server or broker connection is available in this package's
+// tests.
+func BenchmarkTransports(b *testing.B) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	fn := &functions.Function{}
+	fn.Provider.URL = srv.URL
+	evt := &event.Event{}
+
+	b.Run("http", func(b *testing.B) {
+		transport := NewHTTPTransport(nil)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := transport.Send(context.Background(), fn, evt); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("grpc", func(b *testing.B) {
+		transport := NewGRPCTransport(
+			func(target string) (*grpc.ClientConn, error) { return nil, nil },
+			func(ctx context.Context, conn *grpc.ClientConn, fn *functions.Function, evt *event.Event) (*Response, error) {
+				return &Response{StatusCode: http.StatusOK}, nil
+			},
+		)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := transport.Send(context.Background(), fn, evt); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("broker", func(b *testing.B) {
+		transport := &BrokerTransport{
+			Broker:   fakeBroker{},
+			TopicFor: func(*functions.Function) string { return "topic" },
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := transport.Send(context.Background(), fn, evt); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
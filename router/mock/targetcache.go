@@ -8,6 +8,7 @@ import (
 	event "github.com/serverless/event-gateway/event"
 	functions "github.com/serverless/event-gateway/functions"
 	pathtree "github.com/serverless/event-gateway/internal/pathtree"
+	dispatch "github.com/serverless/gateway/router/dispatch"
 )
 
 // Mock of Targeter interface
@@ -61,3 +62,13 @@ func (_m *MockTargeter) SubscribersOfEvent(_param0 string, _param1 event.Type) [
 func (_mr *_MockTargeterRecorder) SubscribersOfEvent(arg0, arg1 interface{}) *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "SubscribersOfEvent", arg0, arg1)
 }
+
+func (_m *MockTargeter) TransportFor(_param0 functions.FunctionID) dispatch.Transport {
+	ret := _m.ctrl.Call(_m, "TransportFor", _param0)
+	ret0, _ := ret[0].(dispatch.Transport)
+	return ret0
+}
+
+func (_mr *_MockTargeterRecorder) TransportFor(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "TransportFor", arg0)
+}
@@ -10,6 +10,9 @@ import (
 	"github.com/serverless/gateway/endpoints"
 	"github.com/serverless/gateway/functions"
 	"github.com/serverless/gateway/pubsub"
+	"github.com/serverless/gateway/router/discovery"
+	"github.com/serverless/gateway/router/dispatch"
+	"github.com/serverless/gateway/targetcache/stream"
 )
 
 // Cache is a simplification of the db.Reactive interface, which doesn't care about
@@ -19,6 +22,24 @@ type Cache interface {
 	Del(string, []byte)
 }
 
+// cacheOptions bundles the dependencies every cache needs beyond its own
+// data structures - logging, event publishing, the wire Codec and payload
+// size limits - so constructors don't grow an unbounded parameter list as
+// the caches pick up more cross-cutting concerns.
+type cacheOptions struct {
+	log    *zap.Logger
+	events *stream.EventPublisher
+	codec  Codec
+	limits SizeLimits
+}
+
+func (o cacheOptions) codecOrDefault() Codec {
+	if o.codec == nil {
+		return identityCodec{}
+	}
+	return o.codec
+}
+
 type cacheMaintainer struct {
 	cache Cache
 }
@@ -47,65 +68,151 @@ func (c *cacheMaintainer) Deleted(key string, lastKnownValue []byte) {
 type functionCache struct {
 	sync.RWMutex
 	// cache maps from FunctionID to Function
-	cache map[functions.FunctionID]functions.Function
-	log   *zap.Logger
+	cache  map[functions.FunctionID]functions.Function
+	log    *zap.Logger
+	events *stream.EventPublisher
+	codec  Codec
+	limits SizeLimits
+
+	// discoveryFactory builds the Instancer/Factory pair for a Function
+	// registered with a DiscoveryConfig. nil disables dynamic discovery
+	// entirely, in which case Functions are only ever resolved from cache.
+	discoveryFactory DiscoveryFactory
+	// endpointers holds the live discovery.Endpointer backing each Function
+	// that was registered with a DiscoveryConfig.
+	endpointers map[functions.FunctionID]*discovery.Endpointer
+
+	// transportFactory builds the dispatch.Transport for a Function
+	// registered with a TransportConfig. nil means every Function dispatches
+	// over the default HTTP transport.
+	transportFactory TransportFactory
+	// transports holds the dispatch.Transport backing each Function that was
+	// registered with a TransportConfig.
+	transports map[functions.FunctionID]dispatch.Transport
 }
 
-func newFunctionCache(log *zap.Logger) *functionCache {
+func newFunctionCache(opts cacheOptions, discoveryFactory DiscoveryFactory, transportFactory TransportFactory) *functionCache {
 	return &functionCache{
-		cache: map[functions.FunctionID]functions.Function{},
-		log:   log,
+		cache:            map[functions.FunctionID]functions.Function{},
+		log:              opts.log,
+		events:           opts.events,
+		codec:            opts.codecOrDefault(),
+		limits:           opts.limits,
+		discoveryFactory: discoveryFactory,
+		endpointers:      map[functions.FunctionID]*discovery.Endpointer{},
+		transportFactory: transportFactory,
+		transports:       map[functions.FunctionID]dispatch.Transport{},
 	}
 }
 
 func (c *functionCache) Set(k string, v []byte) {
+	decoded, err := c.codec.Decode(v)
+	if err != nil {
+		c.log.Error("Could not decode Function payload!", zap.Error(err), zap.String("key", k))
+		return
+	}
+
+	if !checkSizeLimits(c.limits, c.log, k, decoded) {
+		c.events.Publish(stream.Events{{Topic: stream.TopicFunction, Op: stream.OpRejected, Key: k, Payload: decoded}})
+		return
+	}
+
+	if !validTransport(c.log, k, decoded) {
+		return
+	}
+
 	f := functions.Function{}
-	err := json.NewDecoder(bytes.NewReader(v)).Decode(&f)
+	err = json.NewDecoder(bytes.NewReader(decoded)).Decode(&f)
 	if err != nil {
 		c.log.Error("Could not deserialize Function state!", zap.Error(err), zap.String("key", k))
 	} else {
 		c.Lock()
 		defer c.Unlock()
 		c.cache[functions.FunctionID(k)] = f
+		c.setDiscovery(functions.FunctionID(k), decoded)
+		c.setTransport(functions.FunctionID(k), decoded)
+		c.events.Publish(stream.Events{{Topic: stream.TopicFunction, Op: stream.OpSet, Key: k, Payload: decoded}})
 	}
 }
 
 func (c *functionCache) Del(k string, v []byte) {
+	decoded, err := c.codec.Decode(v)
+	if err != nil {
+		c.log.Error("Could not decode Function payload!", zap.Error(err), zap.String("key", k))
+		return
+	}
+
 	c.Lock()
 	defer c.Unlock()
 	delete(c.cache, functions.FunctionID(k))
+	if e, ok := c.endpointers[functions.FunctionID(k)]; ok {
+		e.Close()
+		delete(c.endpointers, functions.FunctionID(k))
+	}
+	if t, ok := c.transports[functions.FunctionID(k)]; ok {
+		if evictor, ok := t.(dispatch.Evictor); ok {
+			evictor.Evict(functions.FunctionID(k))
+		}
+		delete(c.transports, functions.FunctionID(k))
+	}
+	c.events.Publish(stream.Events{{Topic: stream.TopicFunction, Op: stream.OpDel, Key: k, Payload: decoded}})
 }
 
 type endpointCache struct {
 	sync.RWMutex
 	// cache maps from EndpointID to Endpoint
-	cache map[endpoints.EndpointID]endpoints.Endpoint
-	log   *zap.Logger
+	cache  map[endpoints.EndpointID]endpoints.Endpoint
+	log    *zap.Logger
+	events *stream.EventPublisher
+	codec  Codec
+	limits SizeLimits
 }
 
-func newEndpointCache(log *zap.Logger) *endpointCache {
+func newEndpointCache(opts cacheOptions) *endpointCache {
 	return &endpointCache{
-		cache: map[endpoints.EndpointID]endpoints.Endpoint{},
-		log:   log,
+		cache:  map[endpoints.EndpointID]endpoints.Endpoint{},
+		log:    opts.log,
+		events: opts.events,
+		codec:  opts.codecOrDefault(),
+		limits: opts.limits,
 	}
 }
 
 func (c *endpointCache) Set(k string, v []byte) {
+	decoded, err := c.codec.Decode(v)
+	if err != nil {
+		c.log.Error("Could not decode Endpoint payload!", zap.Error(err), zap.String("key", k))
+		return
+	}
+
+	if !checkSizeLimits(c.limits, c.log, k, decoded) {
+		c.events.Publish(stream.Events{{Topic: stream.TopicEndpoint, Op: stream.OpRejected, Key: k, Payload: decoded}})
+		return
+	}
+
 	e := endpoints.Endpoint{}
-	err := json.NewDecoder(bytes.NewReader(v)).Decode(&e)
+	err = json.NewDecoder(bytes.NewReader(decoded)).Decode(&e)
 	if err != nil {
 		c.log.Error("Could not deserialize Endpoint state!", zap.Error(err), zap.String("key", k))
 	} else {
 		c.Lock()
 		defer c.Unlock()
 		c.cache[endpoints.EndpointID(k)] = e
+		c.events.Publish(stream.Events{{Topic: stream.TopicEndpoint, Op: stream.OpSet, Key: k, Payload: decoded}})
 	}
 }
 
 func (c *endpointCache) Del(k string, v []byte) {
+	decoded, err := c.codec.Decode(v)
+	if err != nil {
+		c.log.Error("Could not decode Endpoint payload!", zap.Error(err), zap.String("key", k))
+		return
+	}
+
 	c.Lock()
 	defer c.Unlock()
 	delete(c.cache, endpoints.EndpointID(k))
+	c.events.Publish(stream.Events{{Topic: stream.TopicEndpoint, Op: stream.OpDel, Key: k, Payload: decoded}})
 }
 
 type publisherCache struct {
@@ -120,20 +227,38 @@ type publisherCache struct {
 	// fnOutToTopic maps from FunctionID to a set of TopicID's that consume the output of the function
 	fnOutToTopic map[functions.FunctionID]map[pubsub.TopicID]struct{}
 
-	log *zap.Logger
+	log    *zap.Logger
+	events *stream.EventPublisher
+	codec  Codec
+	limits SizeLimits
 }
 
-func newPublisherCache(log *zap.Logger) *publisherCache {
+func newPublisherCache(opts cacheOptions) *publisherCache {
 	return &publisherCache{
-		log:          log,
+		cache:        map[pubsub.PublisherID]pubsub.Publisher{},
+		log:          opts.log,
+		events:       opts.events,
+		codec:        opts.codecOrDefault(),
+		limits:       opts.limits,
 		fnInToTopic:  map[functions.FunctionID]map[pubsub.TopicID]struct{}{},
 		fnOutToTopic: map[functions.FunctionID]map[pubsub.TopicID]struct{}{},
 	}
 }
 
 func (c *publisherCache) Set(k string, v []byte) {
+	decoded, err := c.codec.Decode(v)
+	if err != nil {
+		c.log.Error("Could not decode Publisher payload!", zap.Error(err), zap.String("key", k))
+		return
+	}
+
+	if !checkSizeLimits(c.limits, c.log, k, decoded) {
+		c.events.Publish(stream.Events{{Topic: stream.TopicPublisher, Op: stream.OpRejected, Key: k, Payload: decoded}})
+		return
+	}
+
 	p := pubsub.Publisher{}
-	err := json.NewDecoder(bytes.NewReader(v)).Decode(&p)
+	err = json.NewDecoder(bytes.NewReader(decoded)).Decode(&p)
 	if err != nil {
 		c.log.Error("Could not deserialize Publisher state!", zap.Error(err), zap.String("key", k))
 		return
@@ -165,9 +290,17 @@ func (c *publisherCache) Set(k string, v []byte) {
 	} else {
 		c.log.Error("received a new Publisher with an invalid FunctionEnd!")
 	}
+
+	c.events.Publish(stream.Events{{Topic: stream.TopicPublisher, Op: stream.OpSet, Key: k, Payload: decoded}})
 }
 
 func (c *publisherCache) Del(k string, v []byte) {
+	decoded, err := c.codec.Decode(v)
+	if err != nil {
+		c.log.Error("Could not decode Publisher payload!", zap.Error(err), zap.String("key", k))
+		return
+	}
+
 	c.Lock()
 	defer c.Unlock()
 
@@ -197,26 +330,49 @@ func (c *publisherCache) Del(k string, v []byte) {
 	} else {
 		c.log.Error("trying to delete a Publisher with an invalid FunctionEnd!")
 	}
+
+	c.events.Publish(stream.Events{{Topic: stream.TopicPublisher, Op: stream.OpDel, Key: k, Payload: decoded}})
 }
 
 type subscriberCache struct {
 	sync.RWMutex
+	// cache keeps deserialized Subscribers around to properly delete them
+	// later and to snapshot the cache.
+	cache map[pubsub.SubscriberID]pubsub.Subscriber
 	// topicToSub maps from a TopicID to a set of subscribing FunctionID's
 	topicToFns map[pubsub.TopicID]map[functions.FunctionID]struct{}
 	log        *zap.Logger
+	events     *stream.EventPublisher
+	codec      Codec
+	limits     SizeLimits
 }
 
-func newSubscriberCache(log *zap.Logger) *subscriberCache {
+func newSubscriberCache(opts cacheOptions) *subscriberCache {
 	return &subscriberCache{
+		cache: map[pubsub.SubscriberID]pubsub.Subscriber{},
 		// topicToFns is a map from TopicID to a set of FunctionID's
 		topicToFns: map[pubsub.TopicID]map[functions.FunctionID]struct{}{},
-		log:        log,
+		log:        opts.log,
+		events:     opts.events,
+		codec:      opts.codecOrDefault(),
+		limits:     opts.limits,
 	}
 }
 
 func (c *subscriberCache) Set(k string, v []byte) {
+	decoded, err := c.codec.Decode(v)
+	if err != nil {
+		c.log.Error("Could not decode Subscriber payload!", zap.Error(err), zap.String("key", k))
+		return
+	}
+
+	if !checkSizeLimits(c.limits, c.log, k, decoded) {
+		c.events.Publish(stream.Events{{Topic: stream.TopicSubscriber, Op: stream.OpRejected, Key: k, Payload: decoded}})
+		return
+	}
+
 	s := pubsub.Subscriber{}
-	err := json.NewDecoder(bytes.NewReader(v)).Decode(&s)
+	err = json.NewDecoder(bytes.NewReader(decoded)).Decode(&s)
 	if err != nil {
 		c.log.Error("Could not deserialize Subscriber state!", zap.Error(err), zap.String("key", k))
 		return
@@ -225,6 +381,8 @@ func (c *subscriberCache) Set(k string, v []byte) {
 	c.Lock()
 	defer c.Unlock()
 
+	c.cache[pubsub.SubscriberID(k)] = s
+
 	// set FunctionID as destination in topicToSub
 	fnSet, exists := c.topicToFns[s.TopicID]
 	if exists {
@@ -234,18 +392,25 @@ func (c *subscriberCache) Set(k string, v []byte) {
 		fnSet[s.FunctionID] = struct{}{}
 		c.topicToFns[s.TopicID] = fnSet
 	}
+
+	c.events.Publish(stream.Events{{Topic: stream.TopicSubscriber, Op: stream.OpSet, Key: k, Payload: decoded}})
 }
 
 func (c *subscriberCache) Del(k string, v []byte) {
+	decoded, err := c.codec.Decode(v)
+	if err != nil {
+		c.log.Error("Could not decode Subscriber payload!", zap.Error(err), zap.String("key", k))
+		return
+	}
+
 	c.Lock()
 	defer c.Unlock()
 
-	oldSub := pubsub.Subscriber{}
-	err := json.NewDecoder(bytes.NewReader(v)).Decode(&oldSub)
-	if err != nil {
-		c.log.Error("Could not deserialize Subscriber state during deletion!", zap.Error(err), zap.String("key", k))
+	oldSub, exists := c.cache[pubsub.SubscriberID(k)]
+	if !exists {
 		return
 	}
+	delete(c.cache, pubsub.SubscriberID(k))
 
 	fnSet, exists := c.topicToFns[oldSub.TopicID]
 	if exists {
@@ -255,4 +420,6 @@ func (c *subscriberCache) Del(k string, v []byte) {
 			delete(c.topicToFns, oldSub.TopicID)
 		}
 	}
-}
\ No newline at end of file
+
+	c.events.Publish(stream.Events{{Topic: stream.TopicSubscriber, Op: stream.OpDel, Key: k, Payload: decoded}})
+}
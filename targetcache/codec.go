@@ -0,0 +1,63 @@
+package targetcache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+)
+
+// Codec seams the wire representation of a cache entry: Encode prepares a
+// value for storage in the KV backend, Decode restores the original JSON
+// bytes a cache's Set expects. The default identityCodec is a no-op so
+// existing deployments keep working unchanged; NewGzipCodec lets large
+// Functions/Endpoints be compressed before they hit etcd's per-value limits.
+type Codec interface {
+	Encode(v []byte) ([]byte, error)
+	Decode(v []byte) ([]byte, error)
+}
+
+type identityCodec struct{}
+
+func (identityCodec) Encode(v []byte) ([]byte, error) { return v, nil }
+func (identityCodec) Decode(v []byte) ([]byte, error) { return v, nil }
+
+// gzipMagic is gzip's own two-byte stream header. Reusing it as the "is this
+// entry compressed" marker means old (uncompressed) and new (gzip) entries
+// can coexist in the KV backend during rollout with no extra framing: Decode
+// only inflates a value when it actually looks like gzip.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+type gzipCodec struct{}
+
+// NewGzipCodec returns a Codec that gzip-compresses every value written
+// through Encode, and transparently decompresses values carrying the gzip
+// magic bytes through Decode, passing anything else through unchanged.
+func NewGzipCodec() Codec {
+	return gzipCodec{}
+}
+
+func (gzipCodec) Encode(v []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(v); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCodec) Decode(v []byte) ([]byte, error) {
+	if !bytes.HasPrefix(v, gzipMagic) {
+		return v, nil
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(v))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return ioutil.ReadAll(r)
+}
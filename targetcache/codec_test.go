@@ -0,0 +1,58 @@
+package targetcache
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGzipCodecRoundTrip(t *testing.T) {
+	codec := NewGzipCodec()
+	original := []byte(`{"hello":"world"}`)
+
+	encoded, err := codec.Encode(original)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if !bytes.HasPrefix(encoded, gzipMagic) {
+		t.Fatal("Encode output doesn't start with the gzip magic bytes")
+	}
+
+	decoded, err := codec.Decode(encoded)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if !bytes.Equal(decoded, original) {
+		t.Fatalf("Decode(Encode(v)) = %q, want %q", decoded, original)
+	}
+}
+
+func TestGzipCodecDecodePassesThroughPlainJSON(t *testing.T) {
+	// Entries written before a gzipCodec rollout are plain JSON and carry no
+	// gzip magic bytes; Decode must return them unchanged rather than
+	// erroring, so old and new entries can coexist in the KV backend during
+	// rollout.
+	plain := []byte(`{"hello":"world"}`)
+
+	decoded, err := NewGzipCodec().Decode(plain)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if !bytes.Equal(decoded, plain) {
+		t.Fatalf("Decode(plain) = %q, want %q unchanged", decoded, plain)
+	}
+}
+
+func TestIdentityCodecIsANoOp(t *testing.T) {
+	v := []byte(`{"hello":"world"}`)
+	codec := identityCodec{}
+
+	encoded, err := codec.Encode(v)
+	if err != nil || !bytes.Equal(encoded, v) {
+		t.Fatalf("Encode(v) = %q, %v; want %q, nil", encoded, err, v)
+	}
+
+	decoded, err := codec.Decode(v)
+	if err != nil || !bytes.Equal(decoded, v) {
+		t.Fatalf("Decode(v) = %q, %v; want %q, nil", decoded, err, v)
+	}
+}
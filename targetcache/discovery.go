@@ -0,0 +1,68 @@
+package targetcache
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"go.uber.org/zap"
+
+	"github.com/serverless/gateway/functions"
+	"github.com/serverless/gateway/router/discovery"
+)
+
+// discoveryEnvelope decodes just the `discovery` key out of a raw Function
+// value, mirroring the Discovery field kept on the full functions.Function
+// schema without requiring targetcache to import every field of it.
+type discoveryEnvelope struct {
+	Discovery *DiscoveryConfig `json:"discovery"`
+}
+
+// DiscoveryConfig selects the service registry backend a Function's
+// instances should be resolved from.
+type DiscoveryConfig struct {
+	Provider string `json:"provider"`
+	Service  string `json:"service"`
+}
+
+// DiscoveryFactory builds the Instancer and conversion Factory backing a
+// Function registered with a DiscoveryConfig. It's supplied by whatever
+// constructs the targetcache so the cache itself stays agnostic of how to
+// reach Consul or etcd.
+type DiscoveryFactory func(cfg DiscoveryConfig) (discovery.Instancer, discovery.Factory, error)
+
+// setDiscovery parses the discovery envelope out of v and, if present,
+// (re)builds the Endpointer backing id. Any previous Endpointer for id is
+// closed first. Must be called with c.Lock already held.
+func (c *functionCache) setDiscovery(id functions.FunctionID, v []byte) {
+	if c.discoveryFactory == nil {
+		return
+	}
+
+	env := discoveryEnvelope{}
+	if err := json.NewDecoder(bytes.NewReader(v)).Decode(&env); err != nil || env.Discovery == nil {
+		return
+	}
+
+	if existing, ok := c.endpointers[id]; ok {
+		existing.Close()
+		delete(c.endpointers, id)
+	}
+
+	instancer, factory, err := c.discoveryFactory(*env.Discovery)
+	if err != nil {
+		c.log.Error("Could not build discovery Instancer for Function",
+			zap.String("function", string(id)), zap.String("provider", env.Discovery.Provider), zap.Error(err))
+		return
+	}
+
+	c.endpointers[id] = discovery.NewEndpointer(instancer, factory, c.log)
+}
+
+// Endpointer returns the live-discovered Endpointer backing id, if it was
+// registered with a DiscoveryConfig.
+func (c *functionCache) Endpointer(id functions.FunctionID) (*discovery.Endpointer, bool) {
+	c.RLock()
+	defer c.RUnlock()
+	e, ok := c.endpointers[id]
+	return e, ok
+}
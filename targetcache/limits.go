@@ -0,0 +1,35 @@
+package targetcache
+
+import "go.uber.org/zap"
+
+// SizeLimits bounds the decoded payload size a cache will accept for a
+// single entry, protecting gateway nodes from a single bad Function or
+// Endpoint definition blowing up memory as caches replicate it.
+type SizeLimits struct {
+	// SoftLimitBytes logs a warning once exceeded but still stores the entry.
+	// 0 disables the soft check.
+	SoftLimitBytes int
+	// HardLimitBytes refuses the entry outright and emits an OpRejected
+	// event instead of storing it. 0 disables the hard check.
+	HardLimitBytes int
+}
+
+// checkSizeLimits logs a warning if payload is over limits.SoftLimitBytes,
+// and reports whether it's within limits.HardLimitBytes. Callers must skip
+// the Set entirely and publish an OpRejected event when ok is false.
+func checkSizeLimits(limits SizeLimits, log *zap.Logger, key string, payload []byte) (ok bool) {
+	size := len(payload)
+
+	if limits.HardLimitBytes > 0 && size > limits.HardLimitBytes {
+		log.Warn("Rejecting oversized cache entry",
+			zap.String("key", key), zap.Int("bytes", size), zap.Int("hardLimitBytes", limits.HardLimitBytes))
+		return false
+	}
+
+	if limits.SoftLimitBytes > 0 && size > limits.SoftLimitBytes {
+		log.Warn("Cache entry exceeds soft size limit",
+			zap.String("key", key), zap.Int("bytes", size), zap.Int("softLimitBytes", limits.SoftLimitBytes))
+	}
+
+	return true
+}
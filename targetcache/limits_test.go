@@ -0,0 +1,69 @@
+package targetcache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/serverless/gateway/functions"
+	"github.com/serverless/gateway/targetcache/stream"
+)
+
+func TestCheckSizeLimits(t *testing.T) {
+	log := zap.NewNop()
+
+	cases := []struct {
+		name   string
+		limits SizeLimits
+		size   int
+		want   bool
+	}{
+		{"no limits configured", SizeLimits{}, 1 << 10, true},
+		{"under soft limit", SizeLimits{SoftLimitBytes: 100}, 50, true},
+		{"over soft limit, no hard limit", SizeLimits{SoftLimitBytes: 100}, 200, true},
+		{"under hard limit", SizeLimits{HardLimitBytes: 100}, 50, true},
+		{"over hard limit", SizeLimits{HardLimitBytes: 100}, 200, false},
+		{"over both soft and hard", SizeLimits{SoftLimitBytes: 50, HardLimitBytes: 100}, 200, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := checkSizeLimits(c.limits, log, "key", make([]byte, c.size)); got != c.want {
+				t.Errorf("checkSizeLimits() = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestFunctionCacheSetEmitsOpRejectedOverHardLimit(t *testing.T) {
+	events := stream.NewEventPublisher(stream.Config{})
+	sub, err := events.Subscribe(&stream.SubscribeRequest{})
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	cache := newFunctionCache(cacheOptions{
+		log:    zap.NewNop(),
+		events: events,
+		limits: SizeLimits{HardLimitBytes: 10},
+	}, nil, nil)
+
+	cache.Set("fn-a", make([]byte, 100))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	got, err := sub.Next(ctx)
+	if err != nil {
+		t.Fatalf("Next: %v", err)
+	}
+	if len(got) != 1 || got[0].Op != stream.OpRejected || got[0].Topic != stream.TopicFunction {
+		t.Fatalf("Next returned %+v, want a single OpRejected/TopicFunction event", got)
+	}
+
+	if _, ok := cache.cache[functions.FunctionID("fn-a")]; ok {
+		t.Fatal("a rejected Set must not populate the cache")
+	}
+}
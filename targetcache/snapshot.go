@@ -0,0 +1,402 @@
+package targetcache
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/serverless/gateway/endpoints"
+	"github.com/serverless/gateway/functions"
+	"github.com/serverless/gateway/pubsub"
+	"github.com/serverless/gateway/router/dispatch"
+	"github.com/serverless/gateway/targetcache/stream"
+)
+
+// snapshotMagic self-describes the binary format Snapshotter writes, so
+// LoadSnapshot can refuse anything it doesn't understand instead of
+// corrupting a cache with garbage.
+var snapshotMagic = [4]byte{'E', 'G', 'S', '1'}
+
+// Snapshotter walks all four targetcache caches under their own locks and
+// serializes them into a single self-describing binary snapshot a new
+// gateway node can bulk-load instead of waiting for the reactive KV watcher
+// to stream every entry in individually.
+type Snapshotter struct {
+	functions   *functionCache
+	endpoints   *endpointCache
+	publishers  *publisherCache
+	subscribers *subscriberCache
+	events      *stream.EventPublisher
+}
+
+// NewSnapshotter builds a Snapshotter over the given caches.
+func NewSnapshotter(fnCache *functionCache, epCache *endpointCache, pubCache *publisherCache, subCache *subscriberCache, events *stream.EventPublisher) *Snapshotter {
+	return &Snapshotter{
+		functions:   fnCache,
+		endpoints:   epCache,
+		publishers:  pubCache,
+		subscribers: subCache,
+		events:      events,
+	}
+}
+
+// WriteTo serializes a snapshot to w: a header (magic + schema version
+// implied by the magic, then the EventPublisher's current index) followed
+// by one length-prefixed section per topic, in function/endpoint/publisher/
+// subscriber order.
+func (s *Snapshotter) WriteTo(w io.Writer) error {
+	fnEntries, err := s.functions.snapshot()
+	if err != nil {
+		return err
+	}
+	epEntries, err := s.endpoints.snapshot()
+	if err != nil {
+		return err
+	}
+	pubEntries, err := s.publishers.snapshot()
+	if err != nil {
+		return err
+	}
+	subEntries, err := s.subscribers.snapshot()
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(snapshotMagic[:]); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.BigEndian, s.events.LastIndex()); err != nil {
+		return err
+	}
+
+	for _, entries := range []map[string][]byte{fnEntries, epEntries, pubEntries, subEntries} {
+		if err := writeSection(w, entries); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ServeHTTP implements GET /v1/targetcache/snapshot, gzip-encoding the
+// binary snapshot produced by WriteTo.
+func (s *Snapshotter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Encoding", "gzip")
+
+	gz := gzip.NewWriter(w)
+	defer gz.Close()
+
+	if err := s.WriteTo(gz); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// LoadSnapshot bulk-populates every cache from a snapshot written by
+// WriteTo. Each cache is rebuilt into a shadow map and swapped in atomically
+// under its own lock; the derived indexes publisherCache.fnInToTopic /
+// fnOutToTopic and subscriberCache.topicToFns are rebuilt from the loaded
+// primary entries afterwards rather than read off the wire, so they can't
+// diverge from what was actually loaded. The snapshot's index is returned so
+// the caller can pass it to Resume (a Subscribe with SubscribeRequest.Index
+// set to index) to catch up on anything published after the snapshot was
+// taken.
+func (s *Snapshotter) LoadSnapshot(r io.Reader) (index uint64, err error) {
+	var magic [4]byte
+	if _, err := io.ReadFull(r, magic[:]); err != nil {
+		return 0, err
+	}
+	if magic != snapshotMagic {
+		return 0, fmt.Errorf("targetcache: unrecognized snapshot format %q", magic)
+	}
+
+	if err := binary.Read(r, binary.BigEndian, &index); err != nil {
+		return 0, err
+	}
+
+	fnEntries, err := readSection(r)
+	if err != nil {
+		return 0, err
+	}
+	epEntries, err := readSection(r)
+	if err != nil {
+		return 0, err
+	}
+	pubEntries, err := readSection(r)
+	if err != nil {
+		return 0, err
+	}
+	subEntries, err := readSection(r)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := s.functions.loadSnapshot(fnEntries); err != nil {
+		return 0, err
+	}
+	if err := s.endpoints.loadSnapshot(epEntries); err != nil {
+		return 0, err
+	}
+	if err := s.publishers.loadSnapshot(pubEntries); err != nil {
+		return 0, err
+	}
+	if err := s.subscribers.loadSnapshot(subEntries); err != nil {
+		return 0, err
+	}
+
+	return index, nil
+}
+
+// writeSection writes a uint32 entry count followed by each entry as
+// length-prefixed key/value pairs.
+func writeSection(w io.Writer, entries map[string][]byte) error {
+	if err := binary.Write(w, binary.BigEndian, uint32(len(entries))); err != nil {
+		return err
+	}
+
+	for k, v := range entries {
+		if err := writeLengthPrefixed(w, []byte(k)); err != nil {
+			return err
+		}
+		if err := writeLengthPrefixed(w, v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func readSection(r io.Reader) (map[string][]byte, error) {
+	var count uint32
+	if err := binary.Read(r, binary.BigEndian, &count); err != nil {
+		return nil, err
+	}
+
+	entries := make(map[string][]byte, count)
+	for i := uint32(0); i < count; i++ {
+		key, err := readLengthPrefixed(r)
+		if err != nil {
+			return nil, err
+		}
+		value, err := readLengthPrefixed(r)
+		if err != nil {
+			return nil, err
+		}
+		entries[string(key)] = value
+	}
+
+	return entries, nil
+}
+
+func writeLengthPrefixed(w io.Writer, b []byte) error {
+	if err := binary.Write(w, binary.BigEndian, uint32(len(b))); err != nil {
+		return err
+	}
+	_, err := w.Write(b)
+	return err
+}
+
+func readLengthPrefixed(r io.Reader) ([]byte, error) {
+	var length uint32
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, length)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (c *functionCache) snapshot() (map[string][]byte, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	entries := make(map[string][]byte, len(c.cache))
+	for id, f := range c.cache {
+		b, err := json.Marshal(f)
+		if err != nil {
+			return nil, err
+		}
+		entries[string(id)] = b
+	}
+	return entries, nil
+}
+
+// loadSnapshot replaces c.cache wholesale with entries, decoded and swapped
+// in atomically under c.Lock. Discovery/Transport wiring is rebuilt the same
+// way Set would for every loaded Function.
+func (c *functionCache) loadSnapshot(entries map[string][]byte) error {
+	shadow := make(map[functions.FunctionID]functions.Function, len(entries))
+	for k, v := range entries {
+		f := functions.Function{}
+		if err := json.Unmarshal(v, &f); err != nil {
+			return err
+		}
+		shadow[functions.FunctionID(k)] = f
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	for id, e := range c.endpointers {
+		e.Close()
+		delete(c.endpointers, id)
+	}
+	for id, t := range c.transports {
+		if evictor, ok := t.(dispatch.Evictor); ok {
+			evictor.Evict(id)
+		}
+		delete(c.transports, id)
+	}
+
+	c.cache = shadow
+	for k, v := range entries {
+		c.setDiscovery(functions.FunctionID(k), v)
+		c.setTransport(functions.FunctionID(k), v)
+	}
+
+	return nil
+}
+
+func (c *endpointCache) snapshot() (map[string][]byte, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	entries := make(map[string][]byte, len(c.cache))
+	for id, e := range c.cache {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return nil, err
+		}
+		entries[string(id)] = b
+	}
+	return entries, nil
+}
+
+func (c *endpointCache) loadSnapshot(entries map[string][]byte) error {
+	shadow := make(map[endpoints.EndpointID]endpoints.Endpoint, len(entries))
+	for k, v := range entries {
+		e := endpoints.Endpoint{}
+		if err := json.Unmarshal(v, &e); err != nil {
+			return err
+		}
+		shadow[endpoints.EndpointID(k)] = e
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	c.cache = shadow
+	return nil
+}
+
+func (c *publisherCache) snapshot() (map[string][]byte, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	entries := make(map[string][]byte, len(c.cache))
+	for id, p := range c.cache {
+		b, err := json.Marshal(p)
+		if err != nil {
+			return nil, err
+		}
+		entries[string(id)] = b
+	}
+	return entries, nil
+}
+
+// loadSnapshot replaces c.cache and rebuilds fnInToTopic/fnOutToTopic purely
+// from the loaded Publishers, so the derived indexes can never disagree with
+// what was actually loaded.
+func (c *publisherCache) loadSnapshot(entries map[string][]byte) error {
+	cache := make(map[pubsub.PublisherID]pubsub.Publisher, len(entries))
+	fnInToTopic := map[functions.FunctionID]map[pubsub.TopicID]struct{}{}
+	fnOutToTopic := map[functions.FunctionID]map[pubsub.TopicID]struct{}{}
+
+	for k, v := range entries {
+		p := pubsub.Publisher{}
+		if err := json.Unmarshal(v, &p); err != nil {
+			return err
+		}
+		cache[pubsub.PublisherID(k)] = p
+
+		switch p.FunctionEnd {
+		case pubsub.Input:
+			addTopic(fnInToTopic, p.FunctionID, p.TopicID)
+		case pubsub.Output:
+			addTopic(fnOutToTopic, p.FunctionID, p.TopicID)
+		default:
+			c.log.Error("snapshot contained a Publisher with an invalid FunctionEnd!")
+		}
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	c.cache = cache
+	c.fnInToTopic = fnInToTopic
+	c.fnOutToTopic = fnOutToTopic
+	return nil
+}
+
+func addTopic(index map[functions.FunctionID]map[pubsub.TopicID]struct{}, fn functions.FunctionID, topic pubsub.TopicID) {
+	topics, exists := index[fn]
+	if !exists {
+		topics = map[pubsub.TopicID]struct{}{}
+		index[fn] = topics
+	}
+	topics[topic] = struct{}{}
+}
+
+func (c *subscriberCache) snapshot() (map[string][]byte, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	entries := make(map[string][]byte, len(c.cache))
+	for id, sub := range c.cache {
+		b, err := json.Marshal(sub)
+		if err != nil {
+			return nil, err
+		}
+		entries[string(id)] = b
+	}
+	return entries, nil
+}
+
+// loadSnapshot replaces c.cache and rebuilds topicToFns purely from the
+// loaded Subscribers.
+func (c *subscriberCache) loadSnapshot(entries map[string][]byte) error {
+	cache := make(map[pubsub.SubscriberID]pubsub.Subscriber, len(entries))
+	topicToFns := map[pubsub.TopicID]map[functions.FunctionID]struct{}{}
+
+	for k, v := range entries {
+		sub := pubsub.Subscriber{}
+		if err := json.Unmarshal(v, &sub); err != nil {
+			return err
+		}
+		cache[pubsub.SubscriberID(k)] = sub
+
+		fnSet, exists := topicToFns[sub.TopicID]
+		if !exists {
+			fnSet = map[functions.FunctionID]struct{}{}
+			topicToFns[sub.TopicID] = fnSet
+		}
+		fnSet[sub.FunctionID] = struct{}{}
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	c.cache = cache
+	c.topicToFns = topicToFns
+	return nil
+}
+
+// Resume subscribes to the event stream from just after a loaded snapshot's
+// index, so a node that just called LoadSnapshot catches up on anything
+// published while the snapshot was in flight.
+func (s *Snapshotter) Resume(snapshotIndex uint64) (*stream.Subscription, error) {
+	return s.events.Subscribe(&stream.SubscribeRequest{Index: snapshotIndex})
+}
@@ -0,0 +1,119 @@
+package targetcache
+
+import (
+	"bytes"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/serverless/gateway/endpoints"
+	"github.com/serverless/gateway/functions"
+	"github.com/serverless/gateway/pubsub"
+	"github.com/serverless/gateway/targetcache/stream"
+)
+
+func newTestCaches() (*functionCache, *endpointCache, *publisherCache, *subscriberCache) {
+	opts := cacheOptions{
+		log:    zap.NewNop(),
+		events: stream.NewEventPublisher(stream.Config{}),
+	}
+	return newFunctionCache(opts, nil, nil),
+		newEndpointCache(opts),
+		newPublisherCache(opts),
+		newSubscriberCache(opts)
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	fnCache, epCache, pubCache, subCache := newTestCaches()
+
+	fnCache.cache[functions.FunctionID("fn-a")] = functions.Function{ID: "fn-a"}
+	epCache.cache[endpoints.EndpointID("ep-a")] = endpoints.Endpoint{}
+	pubCache.cache[pubsub.PublisherID("pub-in")] = pubsub.Publisher{
+		FunctionID: "fn-a", TopicID: "topic-a", FunctionEnd: pubsub.Input,
+	}
+	pubCache.cache[pubsub.PublisherID("pub-out")] = pubsub.Publisher{
+		FunctionID: "fn-a", TopicID: "topic-b", FunctionEnd: pubsub.Output,
+	}
+	subCache.cache[pubsub.SubscriberID("sub-a")] = pubsub.Subscriber{
+		FunctionID: "fn-a", TopicID: "topic-a",
+	}
+
+	events := stream.NewEventPublisher(stream.Config{})
+	snap := NewSnapshotter(fnCache, epCache, pubCache, subCache, events)
+
+	var buf bytes.Buffer
+	if err := snap.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+
+	newFn, newEp, newPub, newSub := newTestCaches()
+	loaded := NewSnapshotter(newFn, newEp, newPub, newSub, events)
+
+	index, err := loaded.LoadSnapshot(&buf)
+	if err != nil {
+		t.Fatalf("LoadSnapshot: %v", err)
+	}
+	if index != events.LastIndex() {
+		t.Fatalf("LoadSnapshot returned index %d, want %d", index, events.LastIndex())
+	}
+
+	if got := newFn.cache[functions.FunctionID("fn-a")]; got.ID != "fn-a" {
+		t.Fatalf("functionCache did not load fn-a, got %+v", got)
+	}
+	if _, ok := newEp.cache[endpoints.EndpointID("ep-a")]; !ok {
+		t.Fatal("endpointCache did not load ep-a")
+	}
+	if got := newPub.cache[pubsub.PublisherID("pub-in")]; got.FunctionID != "fn-a" || got.TopicID != "topic-a" {
+		t.Fatalf("publisherCache did not load pub-in, got %+v", got)
+	}
+	if got := newSub.cache[pubsub.SubscriberID("sub-a")]; got.FunctionID != "fn-a" || got.TopicID != "topic-a" {
+		t.Fatalf("subscriberCache did not load sub-a, got %+v", got)
+	}
+
+	// The derived indexes must be rebuilt from the loaded Publishers/
+	// Subscribers, not read off the wire.
+	if _, ok := newPub.fnInToTopic["fn-a"]["topic-a"]; !ok {
+		t.Fatal("fnInToTopic wasn't rebuilt for fn-a -> topic-a")
+	}
+	if _, ok := newPub.fnOutToTopic["fn-a"]["topic-b"]; !ok {
+		t.Fatal("fnOutToTopic wasn't rebuilt for fn-a -> topic-b")
+	}
+	if _, ok := newSub.topicToFns["topic-a"]["fn-a"]; !ok {
+		t.Fatal("topicToFns wasn't rebuilt for topic-a -> fn-a")
+	}
+}
+
+func TestLoadSnapshotRejectsTruncatedInput(t *testing.T) {
+	fnCache, epCache, pubCache, subCache := newTestCaches()
+	fnCache.cache[functions.FunctionID("fn-a")] = functions.Function{ID: "fn-a"}
+
+	events := stream.NewEventPublisher(stream.Config{})
+	snap := NewSnapshotter(fnCache, epCache, pubCache, subCache, events)
+
+	var buf bytes.Buffer
+	if err := snap.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+
+	// Chop off the last two bytes, which cuts into the final (subscriber)
+	// section's uint32 entry count: readSection must surface this as an error
+	// rather than silently loading a partial snapshot.
+	truncated := bytes.NewReader(buf.Bytes()[:buf.Len()-2])
+
+	newFn, newEp, newPub, newSub := newTestCaches()
+	loaded := NewSnapshotter(newFn, newEp, newPub, newSub, events)
+
+	if _, err := loaded.LoadSnapshot(truncated); err == nil {
+		t.Fatal("LoadSnapshot on truncated input returned nil error, want one")
+	}
+}
+
+func TestLoadSnapshotRejectsUnrecognizedMagic(t *testing.T) {
+	newFn, newEp, newPub, newSub := newTestCaches()
+	events := stream.NewEventPublisher(stream.Config{})
+	loaded := NewSnapshotter(newFn, newEp, newPub, newSub, events)
+
+	if _, err := loaded.LoadSnapshot(bytes.NewReader([]byte("nope"))); err == nil {
+		t.Fatal("LoadSnapshot with a bad magic header returned nil error, want one")
+	}
+}
@@ -0,0 +1,10 @@
+package stream
+
+import "errors"
+
+// ErrSubscriptionClosed is returned by EventPublisher.Subscribe when
+// the requested starting index is no longer retained in the buffer, either
+// because it aged past the configured TTL or was evicted to keep the buffer
+// under its size cap. Callers should re-snapshot the caches they care about
+// and Subscribe again from the new snapshot's index.
+var ErrSubscriptionClosed = errors.New("stream: subscription closed, requested index has been pruned")
@@ -0,0 +1,89 @@
+package stream
+
+import "strings"
+
+// Topic identifies which targetcache a change event describes.
+type Topic string
+
+const (
+	TopicFunction   Topic = "function"
+	TopicEndpoint   Topic = "endpoint"
+	TopicPublisher  Topic = "publisher"
+	TopicSubscriber Topic = "subscriber"
+)
+
+// Op identifies the kind of mutation that produced an Event.
+type Op string
+
+const (
+	OpSet Op = "set"
+	OpDel Op = "del"
+	// OpRejected marks a Set that was refused outright, e.g. because the
+	// decoded payload exceeded a cache's configured hard size limit. Key
+	// still identifies the entry that was rejected, but the cache's map was
+	// never updated.
+	OpRejected Op = "reject"
+)
+
+// Event describes a single Set or Del applied to one of the targetcache
+// caches. Index is assigned by the EventPublisher at Publish time and is
+// monotonically increasing across every topic, so it can be used as a
+// resume token regardless of which caches a subscriber cares about.
+type Event struct {
+	Index   uint64 `json:"index"`
+	Topic   Topic  `json:"topic"`
+	Op      Op     `json:"op"`
+	Key     string `json:"key"`
+	Payload []byte `json:"payload,omitempty"`
+}
+
+// Events is a batch of Events sharing the same Index. A single cache
+// mutation that also touches a derived index (e.g. publisherCache rebuilding
+// fnInToTopic) can therefore be published atomically as one batch.
+type Events []Event
+
+func (e Events) filter(f *Filter) Events {
+	if f == nil || (len(f.Topics) == 0 && f.KeyPrefix == "") {
+		return e
+	}
+
+	out := make(Events, 0, len(e))
+	for _, event := range e {
+		if f.match(event) {
+			out = append(out, event)
+		}
+	}
+	return out
+}
+
+// Filter narrows a Subscription down to the topics and key prefix a
+// consumer cares about. The zero value matches everything.
+type Filter struct {
+	// Topics restricts delivery to this set. A nil/empty set matches every
+	// Topic.
+	Topics map[Topic]struct{}
+	// KeyPrefix restricts delivery to events whose Key has this prefix. An
+	// empty prefix matches every Key.
+	KeyPrefix string
+}
+
+func (f *Filter) match(e Event) bool {
+	if len(f.Topics) > 0 {
+		if _, ok := f.Topics[e.Topic]; !ok {
+			return false
+		}
+	}
+	if f.KeyPrefix != "" && !strings.HasPrefix(e.Key, f.KeyPrefix) {
+		return false
+	}
+	return true
+}
+
+// SubscribeRequest describes a new Subscription against an EventPublisher.
+type SubscribeRequest struct {
+	Filter
+
+	// Index is the last Index the subscriber has already observed. 0 means
+	// "start at the tail and only deliver events published from now on".
+	Index uint64
+}
@@ -0,0 +1,170 @@
+package stream
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// bufferItem is one slot of the eventBuffer's singly linked list. It starts
+// out as an empty placeholder; once linked to a successor it is immutable.
+// nextCond lets any number of Subscriptions block in Next until that link is
+// made.
+type bufferItem struct {
+	index   uint64
+	events  Events
+	created time.Time
+
+	nextLock sync.Mutex
+	nextCond *sync.Cond
+	next     *bufferItem
+}
+
+func newBufferItem() *bufferItem {
+	item := &bufferItem{created: time.Now()}
+	item.nextCond = sync.NewCond(&item.nextLock)
+	return item
+}
+
+// link appends next after i and wakes anyone blocked in Next.
+func (i *bufferItem) link(next *bufferItem) {
+	i.nextLock.Lock()
+	defer i.nextLock.Unlock()
+	i.next = next
+	i.nextCond.Broadcast()
+}
+
+// Next blocks until either i's successor is appended or ctx is done. i may
+// already have been pruned, but prune only ever evicts items that already
+// have a successor (see eventBuffer.prune), so Next on a pruned item returns
+// at once and a caller can only be blocked here while i is the current head. A Subscription positioned on an
+// already-evicted index finds out at Subscribe/StartFromIndex time instead,
+// via ErrSubscriptionClosed.
+func (i *bufferItem) Next(ctx context.Context) (*bufferItem, error) {
+	i.nextLock.Lock()
+	defer i.nextLock.Unlock()
+
+	if i.next != nil {
+		return i.next, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// sync.Cond has no context-aware Wait, so wake the waiter up with a
+	// Broadcast once ctx is done. waitCh lets the goroutine exit once Next
+	// returns through the normal path instead of leaking until ctx expires.
+	waitCh := make(chan struct{})
+	defer close(waitCh)
+	go func() {
+		select {
+		case <-ctx.Done():
+			i.nextLock.Lock()
+			i.nextCond.Broadcast()
+			i.nextLock.Unlock()
+		case <-waitCh:
+		}
+	}()
+
+	for i.next == nil && ctx.Err() == nil {
+		i.nextCond.Wait()
+	}
+
+	if i.next != nil {
+		return i.next, nil
+	}
+	return nil, ctx.Err()
+}
+
+// eventBuffer is a fixed-size, append-only ring of bufferItems. start is the
+// oldest item still retained; head is the newest (always an empty
+// placeholder waiting for the next append). Pruning walks forward from
+// start, evicting items once the buffer holds more than size of them or
+// they've sat longer than ttl. Eviction never affects a Subscription already
+// parked on the evicted item: every evicted item already has its successor
+// linked (prune never reaches head, the only item that doesn't), so an
+// in-flight Next call simply follows the link. It only changes what a fresh
+// StartFromIndex lookup can find, since the evicted item is no longer
+// reachable from the (advanced) start.
+type eventBuffer struct {
+	size int
+	ttl  time.Duration
+
+	mu    sync.Mutex
+	start *bufferItem
+	head  *bufferItem
+	count int
+}
+
+func newEventBuffer(size int, ttl time.Duration) *eventBuffer {
+	sentinel := newBufferItem()
+	return &eventBuffer{
+		size:  size,
+		ttl:   ttl,
+		start: sentinel,
+		head:  sentinel,
+	}
+}
+
+// Head returns the current tail placeholder, i.e. what a Subscribe(index=0)
+// should start waiting from.
+func (b *eventBuffer) Head() *bufferItem {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.head
+}
+
+// Append publishes events as a new item and prunes the buffer down to size/ttl.
+func (b *eventBuffer) Append(events Events) {
+	if len(events) == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	item := newBufferItem()
+	item.index = events[0].Index
+	item.events = events
+
+	b.head.link(item)
+	b.head = item
+	b.count++
+
+	b.prune()
+}
+
+// prune must be called with b.mu held.
+func (b *eventBuffer) prune() {
+	for b.start != b.head {
+		overSize := b.size > 0 && b.count > b.size
+		overTTL := b.ttl > 0 && time.Since(b.start.created) > b.ttl
+		if !overSize && !overTTL {
+			return
+		}
+
+		b.start = b.start.next
+		b.count--
+	}
+}
+
+// StartFromIndex resolves a SubscribeRequest.Index into the bufferItem a
+// Subscription should begin calling Next on. index == 0 resolves to the
+// current tail, meaning the subscriber only sees events published from now
+// on. Any other index must still be retained in the buffer, otherwise
+// ErrSubscriptionClosed is returned.
+func (b *eventBuffer) StartFromIndex(index uint64) (*bufferItem, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if index == 0 {
+		return b.head, nil
+	}
+
+	for item := b.start; item != nil; item = item.next {
+		if item.index == index {
+			return item, nil
+		}
+	}
+	return nil, ErrSubscriptionClosed
+}
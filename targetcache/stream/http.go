@@ -0,0 +1,68 @@
+package stream
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Handler serves GET /v1/events?topic=function&key=foo*&index=123, streaming
+// matching Events as newline-delimited JSON for as long as the client stays
+// connected.
+type Handler struct {
+	Publisher *EventPublisher
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	req := &SubscribeRequest{}
+
+	if topic := r.URL.Query().Get("topic"); topic != "" {
+		req.Topics = map[Topic]struct{}{Topic(topic): {}}
+	}
+	if key := r.URL.Query().Get("key"); key != "" {
+		req.KeyPrefix = strings.TrimSuffix(key, "*")
+	}
+	if idx := r.URL.Query().Get("index"); idx != "" {
+		index, err := strconv.ParseUint(idx, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid index", http.StatusBadRequest)
+			return
+		}
+		req.Index = index
+	}
+
+	sub, err := h.Publisher.Subscribe(req)
+	if err == ErrSubscriptionClosed {
+		http.Error(w, err.Error(), http.StatusGone)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ctx := r.Context()
+	enc := json.NewEncoder(w)
+	for {
+		events, err := sub.Next(ctx)
+		if err != nil {
+			return
+		}
+		for _, event := range events {
+			if err := enc.Encode(event); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
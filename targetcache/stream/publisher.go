@@ -0,0 +1,76 @@
+package stream
+
+import (
+	"sync"
+	"time"
+)
+
+// Config controls the retention of an EventPublisher's ring buffer.
+type Config struct {
+	// EventBufferSize is the maximum number of published batches retained
+	// for replay. 0 disables the size-based eviction (ttl still applies).
+	EventBufferSize int
+	// EventBufferTTL is the maximum age of a retained batch. 0 disables
+	// ttl-based eviction (size still applies).
+	EventBufferTTL time.Duration
+}
+
+// EventPublisher is the write side of the stream: callers Publish batches of
+// Events, which are assigned a monotonic Index and appended to a ring
+// buffer, and Subscribers Subscribe to read them back filtered by topic and
+// key prefix.
+type EventPublisher struct {
+	buffer *eventBuffer
+
+	mu        sync.Mutex
+	lastIndex uint64
+}
+
+// NewEventPublisher constructs an EventPublisher ready to accept Publish and
+// Subscribe calls.
+func NewEventPublisher(cfg Config) *EventPublisher {
+	return &EventPublisher{
+		buffer: newEventBuffer(cfg.EventBufferSize, cfg.EventBufferTTL),
+	}
+}
+
+// Publish appends events to the buffer as a single batch under one
+// monotonic Index, dropping the oldest retained batch(es) if the buffer is
+// over capacity or TTL, and wakes any Subscription blocked in Next.
+func (e *EventPublisher) Publish(events Events) {
+	if len(events) == 0 {
+		return
+	}
+
+	e.mu.Lock()
+	e.lastIndex++
+	index := e.lastIndex
+	e.mu.Unlock()
+
+	for i := range events {
+		events[i].Index = index
+	}
+
+	e.buffer.Append(events)
+}
+
+// LastIndex returns the Index of the most recently published batch, or 0 if
+// nothing has been published yet. Useful for stamping a snapshot so a
+// resuming subscriber knows where to Subscribe from.
+func (e *EventPublisher) LastIndex() uint64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.lastIndex
+}
+
+// Subscribe returns a Subscription that yields Events matching req.Filter
+// starting after req.Index (0 meaning "tail, future events only"). If
+// req.Index has already been pruned from the buffer, ErrSubscriptionClosed
+// is returned immediately so the caller can re-snapshot and resume.
+func (e *EventPublisher) Subscribe(req *SubscribeRequest) (*Subscription, error) {
+	item, err := e.buffer.StartFromIndex(req.Index)
+	if err != nil {
+		return nil, err
+	}
+	return newSubscription(item, req.Filter), nil
+}
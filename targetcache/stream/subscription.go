@@ -0,0 +1,33 @@
+package stream
+
+import "context"
+
+// Subscription streams Events matching a Filter starting from the index it
+// was created with. It is not safe for concurrent use by multiple
+// goroutines.
+type Subscription struct {
+	filter Filter
+	item   *bufferItem
+}
+
+func newSubscription(item *bufferItem, filter Filter) *Subscription {
+	return &Subscription{item: item, filter: filter}
+}
+
+// Next blocks until an Event matching the Subscription's Filter is
+// available, or ctx is done. ErrSubscriptionClosed never comes from here:
+// a Subscription whose starting index has already been pruned is rejected
+// up front by Subscribe/Resume instead (see eventBuffer.StartFromIndex).
+func (s *Subscription) Next(ctx context.Context) (Events, error) {
+	for {
+		next, err := s.item.Next(ctx)
+		if err != nil {
+			return nil, err
+		}
+		s.item = next
+
+		if matched := next.events.filter(&s.filter); len(matched) > 0 {
+			return matched, nil
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package targetcache
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"go.uber.org/zap"
+
+	"github.com/serverless/gateway/functions"
+	"github.com/serverless/gateway/router/dispatch"
+)
+
+// TransportConfig selects which router/dispatch.Transport a Function's
+// events should be delivered over, mirroring the Transport discriminator
+// kept on the full functions.Function schema.
+type TransportConfig struct {
+	Kind   string          `json:"kind"`
+	Config json.RawMessage `json:"config,omitempty"`
+}
+
+// transportEnvelope decodes just the `transport` key out of a raw Function
+// value, the same trick discoveryEnvelope uses for `discovery`.
+type transportEnvelope struct {
+	Transport *TransportConfig `json:"transport"`
+}
+
+// validTransportKinds lists the Transport discriminators functionCache.Set
+// accepts. A Function with an unrecognized kind is rejected outright rather
+// than silently falling back to HTTP dispatch.
+var validTransportKinds = map[string]struct{}{
+	"http":  {},
+	"grpc":  {},
+	"nats":  {},
+	"kafka": {},
+}
+
+// validTransport decodes the transport envelope out of v and reports
+// whether its Kind (if any; a Function with no transport config defaults to
+// HTTP dispatch) is one functionCache recognizes.
+func validTransport(log *zap.Logger, key string, v []byte) bool {
+	env := transportEnvelope{}
+	if err := json.NewDecoder(bytes.NewReader(v)).Decode(&env); err != nil {
+		log.Error("Could not parse Function transport config!", zap.Error(err), zap.String("key", key))
+		return false
+	}
+
+	if env.Transport == nil {
+		return true
+	}
+
+	if _, ok := validTransportKinds[env.Transport.Kind]; !ok {
+		log.Error("Function has an unrecognized Transport kind!",
+			zap.String("key", key), zap.String("kind", env.Transport.Kind))
+		return false
+	}
+
+	return true
+}
+
+// TransportFactory builds the dispatch.Transport backing a TransportConfig.
+// It's supplied by whatever constructs the targetcache, keeping
+// functionCache itself agnostic of how to dial gRPC or connect to a broker.
+type TransportFactory func(cfg TransportConfig) (dispatch.Transport, error)
+
+// setTransport parses the transport envelope out of v and, if both a config
+// and factory are present, (re)builds the Transport backing id. Any
+// per-Function state the previous Transport held for id is evicted first,
+// the same way setDiscovery closes the previous Endpointer. Must be called
+// with c.Lock already held.
+func (c *functionCache) setTransport(id functions.FunctionID, v []byte) {
+	if c.transportFactory == nil {
+		return
+	}
+
+	env := transportEnvelope{}
+	if err := json.NewDecoder(bytes.NewReader(v)).Decode(&env); err != nil || env.Transport == nil {
+		return
+	}
+
+	if old, ok := c.transports[id]; ok {
+		if evictor, ok := old.(dispatch.Evictor); ok {
+			evictor.Evict(id)
+		}
+	}
+
+	transport, err := c.transportFactory(*env.Transport)
+	if err != nil {
+		c.log.Error("Could not build Transport for Function",
+			zap.String("function", string(id)), zap.String("kind", env.Transport.Kind), zap.Error(err))
+		return
+	}
+
+	c.transports[id] = transport
+}
+
+// TransportFor returns the dispatch.Transport backing id, if it was
+// registered with a TransportConfig. Callers should fall back to HTTP
+// dispatch when ok is false.
+func (c *functionCache) TransportFor(id functions.FunctionID) (dispatch.Transport, bool) {
+	c.RLock()
+	defer c.RUnlock()
+	t, ok := c.transports[id]
+	return t, ok
+}